@@ -0,0 +1,280 @@
+package hibe
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/bn256"
+)
+
+// ParamsBB2 represents the system parameters for the BB2 variant of the
+// scheme, a Sakai-Kasahara-style construction that needs a single pairing at
+// decryption time and has private keys of one G1 element, in exchange for
+// private keys that can only be issued directly by the holder of the master
+// key, the opposite trade-off from Params, which supports delegation. Its
+// public parameters are not smaller than those of Params: each h is an
+// element of G2 rather than G1, so for a maximum depth above 1 they are
+// larger.
+type ParamsBB2 struct {
+	g  *bn256.G2
+	g1 *bn256.G2
+	g2 *bn256.G1
+	h  []*bn256.G2
+
+	// Cached value of e(g2, g).
+	pairing *bn256.GT
+}
+
+// MasterKeyBB2 represents the master secret for a BB2 hierarchy: the
+// exponent alpha used to blind the top-level identity component, plus one
+// blinding exponent beta per supported level of the hierarchy.
+type MasterKeyBB2 struct {
+	alpha *big.Int
+	beta  []*big.Int
+}
+
+// PrivateKeyBB2 represents a key for an ID in a BB2 hierarchy that can
+// decrypt messages encrypted with that ID. Unlike PrivateKey, it cannot be
+// used to derive keys for children of that ID; every PrivateKeyBB2 must be
+// issued directly by KeyGenBB2.
+type PrivateKeyBB2 struct {
+	a0 *bn256.G1
+}
+
+// CiphertextBB2 represents a message encrypted under BB2.
+type CiphertextBB2 struct {
+	c1 *bn256.G2
+	c2 *bn256.GT
+}
+
+// MaximumDepth returns the maximum depth of the hierarchy. This was specified
+// via the "l" argument when SetupBB2 was called.
+func (params *ParamsBB2) MaximumDepth() int {
+	return len(params.h)
+}
+
+// SetupBB2 generates the system parameters for a BB2 hierarchy of maximum
+// depth l, along with the master key from which private keys for any ID are
+// derived.
+func SetupBB2(random io.Reader, l int) (*ParamsBB2, *MasterKeyBB2, error) {
+	params := &ParamsBB2{}
+	master := &MasterKeyBB2{}
+	var err error
+
+	_, params.g, err = bn256.RandomG2(random)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	master.alpha, err = rand.Int(random, bn256.Order)
+	if err != nil {
+		return nil, nil, err
+	}
+	params.g1 = new(bn256.G2).ScalarMult(params.g, master.alpha)
+
+	_, params.g2, err = bn256.RandomG1(random)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	master.beta = make([]*big.Int, l)
+	params.h = make([]*bn256.G2, l)
+	for i := range master.beta {
+		master.beta[i], err = rand.Int(random, bn256.Order)
+		if err != nil {
+			return nil, nil, err
+		}
+		params.h[i] = new(bn256.G2).ScalarMult(params.g, master.beta[i])
+	}
+
+	return params, master, nil
+}
+
+// idExponent computes alpha + sum(beta[i] * id[i]) mod p, the denominator of
+// the private key's exponent and the exponent of the ciphertext's g-term.
+func idExponent(alpha *big.Int, beta []*big.Int, id []*big.Int) *big.Int {
+	exponent := new(big.Int).Set(alpha)
+	for i, component := range id {
+		term := new(big.Int).Mul(beta[i], component)
+		exponent.Add(exponent, term)
+	}
+	return exponent.Mod(exponent, bn256.Order)
+}
+
+// KeyGenBB2 generates a BB2 key for an ID using the master key.
+func KeyGenBB2(params *ParamsBB2, master *MasterKeyBB2, id []*big.Int) (*PrivateKeyBB2, error) {
+	k := len(id)
+	if k > len(master.beta) {
+		panic("Cannot generate key at greater than maximum depth.")
+	}
+
+	exponent := idExponent(master.alpha, master.beta, id)
+	inverse := new(big.Int).ModInverse(exponent, bn256.Order)
+	if inverse == nil {
+		return nil, errors.New("hibe: identity collides with the master secret")
+	}
+
+	return &PrivateKeyBB2{a0: new(bn256.G1).ScalarMult(params.g2, inverse)}, nil
+}
+
+// EncryptBB2 converts the provided message to BB2 ciphertext, using the
+// provided ID as the public key.
+func EncryptBB2(random io.Reader, params *ParamsBB2, id []*big.Int, message *bn256.GT) (*CiphertextBB2, error) {
+	k := len(id)
+	if k > len(params.h) {
+		panic("Cannot encrypt at greater than maximum depth.")
+	}
+
+	s, err := rand.Int(random, bn256.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	base := new(bn256.G2).ScalarMult(params.g1, big.NewInt(1))
+	for i := 0; i != k; i++ {
+		base.Add(base, new(bn256.G2).ScalarMult(params.h[i], id[i]))
+	}
+
+	if params.pairing == nil {
+		params.pairing = bn256.Pair(params.g2, params.g)
+	}
+
+	ciphertext := &CiphertextBB2{}
+	ciphertext.c1 = new(bn256.G2).ScalarMult(base, s)
+	ciphertext.c2 = new(bn256.GT).ScalarMult(params.pairing, s)
+	ciphertext.c2.Add(ciphertext.c2, message)
+
+	return ciphertext, nil
+}
+
+// DecryptBB2 recovers the original message from the provided BB2 ciphertext,
+// using the provided BB2 private key. Unlike Decrypt, this requires only a
+// single pairing operation: e(key.a0, ciphertext.c1) directly recovers
+// e(g2, g)^s, since a0 carries the inverse of the identity exponent computed
+// by idExponent and c1 carries that exponent times s, so the
+// identity-dependent factors cancel modulo the group order.
+func DecryptBB2(key *PrivateKeyBB2, ciphertext *CiphertextBB2) *bn256.GT {
+	shared := bn256.Pair(key.a0, ciphertext.c1)
+	plaintext := new(bn256.GT).Neg(shared)
+	plaintext.Add(plaintext, ciphertext.c2)
+	return plaintext
+}
+
+// MarshalRaw encodes the parameters as a byte slice, without the versioned
+// header that Marshal prepends.
+func (params *ParamsBB2) MarshalRaw() []byte {
+	l := len(params.h)
+	marshalled := make([]byte, (5+2*l)<<geShift)
+
+	copy(geIndex(marshalled, 0, 2), params.g.Marshal())
+	copy(geIndex(marshalled, 2, 2), params.g1.Marshal())
+	copy(geIndex(marshalled, 4, 1), params.g2.Marshal())
+	for i, hi := range params.h {
+		copy(geIndex(marshalled, 5+2*i, 2), hi.Marshal())
+	}
+
+	return marshalled
+}
+
+// UnmarshalRaw recovers the parameters from a byte slice encoded by
+// MarshalRaw, i.e. without a versioned header.
+func (params *ParamsBB2) UnmarshalRaw(marshalled []byte) (*ParamsBB2, bool) {
+	if len(marshalled)&((1<<geShift)-1) != 0 {
+		return nil, false
+	}
+	units := len(marshalled) >> geShift
+	if units < 5 || (units-5)%2 != 0 {
+		return nil, false
+	}
+	l := (units - 5) / 2
+
+	params.g = new(bn256.G2)
+	if _, ok := params.g.Unmarshal(geIndex(marshalled, 0, 2)); !ok {
+		return nil, false
+	}
+
+	params.g1 = new(bn256.G2)
+	if _, ok := params.g1.Unmarshal(geIndex(marshalled, 2, 2)); !ok {
+		return nil, false
+	}
+
+	params.g2 = new(bn256.G1)
+	if _, ok := params.g2.Unmarshal(geIndex(marshalled, 4, 1)); !ok {
+		return nil, false
+	}
+
+	params.h = make([]*bn256.G2, l)
+	for i := range params.h {
+		hi := new(bn256.G2)
+		params.h[i] = hi
+		if _, ok := hi.Unmarshal(geIndex(marshalled, 5+2*i, 2)); !ok {
+			return params, false
+		}
+	}
+
+	// Clear any cached values
+	params.pairing = nil
+
+	return params, true
+}
+
+// MarshalRaw encodes the private key as a byte slice, without the versioned
+// header that Marshal prepends.
+func (key *PrivateKeyBB2) MarshalRaw() []byte {
+	marshalled := make([]byte, 1<<geShift)
+	copy(geIndex(marshalled, 0, 1), key.a0.Marshal())
+	return marshalled
+}
+
+// UnmarshalRaw recovers the private key from a byte slice encoded by
+// MarshalRaw, i.e. without a versioned header.
+func (key *PrivateKeyBB2) UnmarshalRaw(marshalled []byte) (*PrivateKeyBB2, bool) {
+	if len(marshalled) != 1<<geShift {
+		return nil, false
+	}
+
+	key.a0 = new(bn256.G1)
+	if _, ok := key.a0.Unmarshal(geIndex(marshalled, 0, 1)); !ok {
+		return nil, false
+	}
+
+	return key, true
+}
+
+// MarshalRaw encodes the ciphertext as a byte slice, without the versioned
+// header that Marshal prepends.
+func (ciphertext *CiphertextBB2) MarshalRaw() []byte {
+	marshalled := make([]byte, 8<<geShift)
+
+	copy(geIndex(marshalled, 0, 2), ciphertext.c1.Marshal())
+	copy(geIndex(marshalled, 2, 6), ciphertext.c2.Marshal())
+
+	return marshalled
+}
+
+// UnmarshalRaw recovers the ciphertext from a byte slice encoded by
+// MarshalRaw, i.e. without a versioned header. Both components are
+// unmarshalled unconditionally, regardless of whether the other was
+// malformed, for the same reason as Ciphertext.UnmarshalRaw: so that whether
+// UnmarshalRaw returns false does not depend on which component (if any) is
+// invalid via an early return. As with Ciphertext.UnmarshalRaw, this does
+// not make decoding constant-time overall, since bn256's own G1/G2 Unmarshal
+// has data-dependent branches of its own.
+func (ciphertext *CiphertextBB2) UnmarshalRaw(marshalled []byte) (*CiphertextBB2, bool) {
+	if len(marshalled) != 8<<geShift {
+		return nil, false
+	}
+
+	ciphertext.c1 = new(bn256.G2)
+	_, c1Ok := ciphertext.c1.Unmarshal(geIndex(marshalled, 0, 2))
+	ciphertext.c2 = new(bn256.GT)
+	_, c2Ok := ciphertext.c2.Unmarshal(geIndex(marshalled, 2, 6))
+
+	if !c1Ok || !c2Ok {
+		return nil, false
+	}
+
+	return ciphertext, true
+}
@@ -0,0 +1,134 @@
+package hibe
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestBB2TopLevel(t *testing.T) {
+	// Set up parameters
+	params, master, err := SetupBB2(rand.Reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Come up with a message to encrypt
+	message := NewMessage()
+
+	// Encrypt a message under the top level public key
+	ciphertext, err := EncryptBB2(rand.Reader, params, LINEAR_HIERARCHY[:1], message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Generate key for the top level
+	key, err := KeyGenBB2(params, master, LINEAR_HIERARCHY[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Decrypt ciphertext with key and check that it is correct
+	decrypted := DecryptBB2(key, ciphertext)
+	if !bytes.Equal(message.Marshal(), decrypted.Marshal()) {
+		t.Fatal("Original and encrypted messages differ")
+	}
+}
+
+func TestBB2SecondLevel(t *testing.T) {
+	// Set up parameters
+	params, master, err := SetupBB2(rand.Reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Come up with a message to encrypt
+	message := NewMessage()
+
+	// Encrypt a message under the second level public key
+	ciphertext, err := EncryptBB2(rand.Reader, params, LINEAR_HIERARCHY[:2], message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Generate second level key directly from the master key
+	key, err := KeyGenBB2(params, master, LINEAR_HIERARCHY[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted := DecryptBB2(key, ciphertext)
+	if !bytes.Equal(message.Marshal(), decrypted.Marshal()) {
+		t.Fatal("Original and encrypted messages differ")
+	}
+}
+
+func TestBB2TopLevelWithMarshalling(t *testing.T) {
+	// Set up parameters
+	params, master, err := SetupBB2(rand.Reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parambytes := params.Marshal()
+	params = new(ParamsBB2)
+	if _, ok := params.Unmarshal(parambytes); !ok {
+		t.Fatal("Could not unmarshal ParamsBB2")
+	}
+
+	// Come up with a message to encrypt
+	message := NewMessage()
+
+	// Encrypt a message under the top level public key
+	ciphertext, err := EncryptBB2(rand.Reader, params, LINEAR_HIERARCHY[:1], message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertextbytes := ciphertext.Marshal()
+	ciphertext = new(CiphertextBB2)
+	if _, ok := ciphertext.Unmarshal(ciphertextbytes); !ok {
+		t.Fatal("Could not unmarshal CiphertextBB2")
+	}
+
+	// Generate key for the top level
+	key, err := KeyGenBB2(params, master, LINEAR_HIERARCHY[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keybytes := key.Marshal()
+	key = new(PrivateKeyBB2)
+	if _, ok := key.Unmarshal(keybytes); !ok {
+		t.Fatal("Could not unmarshal PrivateKeyBB2")
+	}
+
+	decrypted := DecryptBB2(key, ciphertext)
+	if !bytes.Equal(message.Marshal(), decrypted.Marshal()) {
+		t.Fatal("Original and decrypted messages differ")
+	}
+}
+
+func TestBB2WrongKeyFails(t *testing.T) {
+	params, master, err := SetupBB2(rand.Reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := NewMessage()
+
+	ciphertext, err := EncryptBB2(rand.Reader, params, LINEAR_HIERARCHY[:1], message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := KeyGenBB2(params, master, LINEAR_HIERARCHY[1:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted := DecryptBB2(key, ciphertext)
+	if bytes.Equal(message.Marshal(), decrypted.Marshal()) {
+		t.Fatal("Decryption under the wrong identity should not recover the original message")
+	}
+}
@@ -0,0 +1,92 @@
+package hibe
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestCCAEncryptDecrypt(t *testing.T) {
+	params, master, err := Setup(rand.Reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := LINEAR_HIERARCHY[:2]
+	key, err := KeyGenFromMaster(rand.Reader, params, master, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("a message protected against active adversaries")
+
+	ciphertext, err := EncryptCCA(rand.Reader, params, id, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := DecryptCCA(params, key, id, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, decrypted) {
+		t.Fatal("Original and decrypted plaintexts differ")
+	}
+}
+
+func TestCCADetectsTamperedCiphertext(t *testing.T) {
+	params, master, err := Setup(rand.Reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := LINEAR_HIERARCHY[:1]
+	key, err := KeyGenFromMaster(rand.Reader, params, master, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext, err := EncryptCCA(rand.Reader, params, id, []byte("message"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext.c[0] ^= 0xff
+
+	if _, err := DecryptCCA(params, key, id, ciphertext); err != ErrInvalidCCACiphertext {
+		t.Fatal("expected tampered ciphertext to be rejected")
+	}
+}
+
+func TestCCAEncryptDecryptWithMarshalling(t *testing.T) {
+	params, master, err := Setup(rand.Reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := LINEAR_HIERARCHY[:1]
+	key, err := KeyGenFromMaster(rand.Reader, params, master, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("roundtrip through the wire format")
+
+	ciphertext, err := EncryptCCA(rand.Reader, params, id, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	marshalled := ciphertext.Marshal()
+	ciphertext = new(CCACiphertext)
+	if _, ok := ciphertext.Unmarshal(marshalled); !ok {
+		t.Fatal("Could not unmarshal CCACiphertext")
+	}
+
+	decrypted, err := DecryptCCA(params, key, id, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, decrypted) {
+		t.Fatal("Original and decrypted plaintexts differ")
+	}
+}
@@ -80,6 +80,45 @@ func TestSecondLevelFromMaster(t *testing.T) {
 	}
 }
 
+// BenchmarkEncryptSameIdentity measures repeated encryption to the same
+// identity path under the same params, where params.pairing is computed once
+// and reused rather than recomputed on every call.
+func BenchmarkEncryptSameIdentity(b *testing.B) {
+	params, _, err := Setup(rand.Reader, 10)
+	if err != nil {
+		b.Fatal(err)
+	}
+	params.Precache()
+	message := NewMessage()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Encrypt(rand.Reader, params, LINEAR_HIERARCHY[:2], message); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkEncryptSameIdentityUncached is identical to
+// BenchmarkEncryptSameIdentity, except that it clears params.pairing before
+// every call, forcing it to be recomputed each time. The difference between
+// the two demonstrates the benefit of caching the pairing on Params.
+func BenchmarkEncryptSameIdentityUncached(b *testing.B) {
+	params, _, err := Setup(rand.Reader, 10)
+	if err != nil {
+		b.Fatal(err)
+	}
+	message := NewMessage()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		params.pairing = nil
+		if _, err := Encrypt(rand.Reader, params, LINEAR_HIERARCHY[:2], message); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func TestSecondLevelFromParent(t *testing.T) {
 	// Set up parameters
 	params, key, err := Setup(rand.Reader, 10)
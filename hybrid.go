@@ -0,0 +1,165 @@
+package hibe
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+
+	"golang.org/x/crypto/bn256"
+)
+
+// randomGT samples a uniformly random element of GT by raising the fixed
+// base e(g1, g2) (see gtBase in utils.go) to a random exponent in Zp.
+func randomGT(random io.Reader) (*bn256.GT, error) {
+	if gtBase == nil {
+		gtBase = bn256.Pair(new(bn256.G1).ScalarBaseMult(big.NewInt(1)),
+			new(bn256.G2).ScalarBaseMult(big.NewInt(1)))
+	}
+	exponent, err := rand.Int(random, bn256.Order)
+	if err != nil {
+		return nil, err
+	}
+	return new(bn256.GT).ScalarMult(gtBase, exponent), nil
+}
+
+// hkdfInfo is the HKDF info string used to derive the symmetric key from the
+// HIBE-encrypted GT element. Binding the derivation to the identity path
+// ensures that the same underlying GT element can never be reused to derive a
+// key for a different identity. Each component is length-prefixed, so that
+// distinct identity paths never produce the same info string.
+func hkdfInfo(id []*big.Int) []byte {
+	info := make([]byte, 0, 36*len(id))
+	for _, component := range id {
+		b := component.Bytes()
+		var lenbytes [4]byte
+		binary.BigEndian.PutUint32(lenbytes[:], uint32(len(b)))
+		info = append(info, lenbytes[:]...)
+		info = append(info, b...)
+	}
+	return info
+}
+
+// deriveSymmetricKey expands the marshalled GT element k into a 32-byte
+// ChaCha20-Poly1305 key via HKDF-SHA256, bound to the identity path.
+func deriveSymmetricKey(k *bn256.GT, id []*big.Int) ([]byte, error) {
+	kdf := hkdf.New(sha256.New, k.Marshal(), nil, hkdfInfo(id))
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(kdf, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// HybridCiphertext is the result of encrypting a plaintext of arbitrary
+// length with EncryptHybrid. It consists of a HIBE ciphertext encapsulating a
+// random GT element, together with the XChaCha20-Poly1305 sealed box derived
+// from that element.
+type HybridCiphertext struct {
+	kem   *Ciphertext
+	nonce []byte
+	box   []byte
+}
+
+// EncryptHybrid encrypts a plaintext of arbitrary length to the identity
+// path id. It samples a random GT element, HIBE-encrypts it as the key
+// encapsulation, derives a symmetric key from it via HKDF-SHA256, and seals
+// the plaintext with XChaCha20-Poly1305 under a random nonce. This allows
+// messages larger than a single GT element (384 bytes when marshalled) to
+// be encrypted, at the cost of a symmetric-cipher layer on top of the
+// existing GT-only Encrypt.
+func EncryptHybrid(random io.Reader, params *Params, id []*big.Int, plaintext []byte) (*HybridCiphertext, error) {
+	k, err := randomGT(random)
+	if err != nil {
+		return nil, err
+	}
+
+	kem, err := Encrypt(random, params, id, k)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := deriveSymmetricKey(k, id)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(random, nonce); err != nil {
+		return nil, err
+	}
+
+	box := aead.Seal(nil, nonce, plaintext, nil)
+
+	return &HybridCiphertext{kem: kem, nonce: nonce, box: box}, nil
+}
+
+// DecryptHybrid recovers the plaintext sealed by EncryptHybrid, using the
+// private key for the identity path under which it was encrypted. It returns
+// an error if the AEAD tag does not verify, e.g. because the ciphertext was
+// tampered with or decrypted under the wrong key.
+func DecryptHybrid(key *PrivateKey, ciphertext *HybridCiphertext, id []*big.Int) ([]byte, error) {
+	k := Decrypt(key, ciphertext.kem)
+
+	symkey, err := deriveSymmetricKey(k, id)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := chacha20poly1305.NewX(symkey)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Open(nil, ciphertext.nonce, ciphertext.box, nil)
+}
+
+// Marshal encodes the hybrid ciphertext as a byte slice: the length-prefixed
+// HIBE ciphertext, followed by the nonce, followed by the AEAD ciphertext.
+func (ciphertext *HybridCiphertext) Marshal() []byte {
+	kembytes := ciphertext.kem.MarshalRaw()
+
+	marshalled := make([]byte, 4+len(kembytes)+len(ciphertext.nonce)+len(ciphertext.box))
+	binary.BigEndian.PutUint32(marshalled[0:4], uint32(len(kembytes)))
+	offset := 4
+	offset += copy(marshalled[offset:], kembytes)
+	offset += copy(marshalled[offset:], ciphertext.nonce)
+	copy(marshalled[offset:], ciphertext.box)
+
+	return marshalled
+}
+
+// Unmarshal recovers the hybrid ciphertext from an encoded byte slice.
+// nonceSize must match the nonce size used when the ciphertext was produced,
+// which for EncryptHybrid is chacha20poly1305.NonceSizeX.
+func (ciphertext *HybridCiphertext) Unmarshal(marshalled []byte, nonceSize int) (*HybridCiphertext, bool) {
+	if len(marshalled) < 4 {
+		return nil, false
+	}
+	kemlen := int(binary.BigEndian.Uint32(marshalled[0:4]))
+	if kemlen < 0 || len(marshalled) < 4+kemlen+nonceSize {
+		return nil, false
+	}
+
+	ciphertext.kem = new(Ciphertext)
+	if _, ok := ciphertext.kem.UnmarshalRaw(marshalled[4 : 4+kemlen]); !ok {
+		return nil, false
+	}
+
+	ciphertext.nonce = make([]byte, nonceSize)
+	copy(ciphertext.nonce, marshalled[4+kemlen:4+kemlen+nonceSize])
+
+	ciphertext.box = make([]byte, len(marshalled)-4-kemlen-nonceSize)
+	copy(ciphertext.box, marshalled[4+kemlen+nonceSize:])
+
+	return ciphertext, true
+}
@@ -0,0 +1,71 @@
+package hibe
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+func TestHybridEncryptDecrypt(t *testing.T) {
+	params, master, err := Setup(rand.Reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := LINEAR_HIERARCHY[:2]
+	key, err := KeyGenFromMaster(rand.Reader, params, master, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("a message longer than a single GT element could hold on its own")
+
+	ciphertext, err := EncryptHybrid(rand.Reader, params, id, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := DecryptHybrid(key, ciphertext, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, decrypted) {
+		t.Fatal("Original and decrypted plaintexts differ")
+	}
+}
+
+func TestHybridEncryptDecryptWithMarshalling(t *testing.T) {
+	params, master, err := Setup(rand.Reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := LINEAR_HIERARCHY[:1]
+	key, err := KeyGenFromMaster(rand.Reader, params, master, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("roundtrip through the wire format")
+
+	ciphertext, err := EncryptHybrid(rand.Reader, params, id, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	marshalled := ciphertext.Marshal()
+	ciphertext = new(HybridCiphertext)
+	if _, ok := ciphertext.Unmarshal(marshalled, chacha20poly1305.NonceSizeX); !ok {
+		t.Fatal("Could not unmarshal HybridCiphertext")
+	}
+
+	decrypted, err := DecryptHybrid(key, ciphertext, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, decrypted) {
+		t.Fatal("Original and decrypted plaintexts differ")
+	}
+}
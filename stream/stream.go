@@ -0,0 +1,262 @@
+// Package stream implements chunked streaming encryption on top of the HIBE
+// key encapsulation provided by the hibe package. A Writer derives a
+// symmetric key via HIBE once per stream, then seals the payload as a
+// sequence of fixed-size chunks, so that neither side needs to buffer the
+// whole message in memory. This makes HIBE usable for large files and
+// network streams.
+package stream
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/samkumar/hibe"
+)
+
+// chunkSize is the amount of plaintext sealed into each chunk.
+const chunkSize = 64 * 1024
+
+// noncePrefixSize is the size, in bytes, of the random prefix chosen once per
+// stream and mixed into every chunk's nonce.
+const noncePrefixSize = 12
+
+// counterSize is the size, in bytes, of the big-endian chunk counter mixed
+// into every chunk's nonce.
+const counterSize = 11
+
+const lastChunkFlag byte = 1
+const notLastChunkFlag byte = 0
+
+// streamInfo is the HKDF info string used to derive the per-stream symmetric
+// key from the HIBE-encapsulated GT element.
+var streamInfo = []byte("hibe/stream")
+
+func deriveKey(k []byte) ([]byte, error) {
+	kdf := hkdf.New(sha256.New, k, nil, streamInfo)
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(kdf, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func nonce(prefix []byte, counter uint64, last bool) []byte {
+	n := make([]byte, noncePrefixSize+counterSize+1)
+	copy(n, prefix)
+	binary.BigEndian.PutUint64(n[noncePrefixSize+counterSize-8:noncePrefixSize+counterSize], counter)
+	if last {
+		n[len(n)-1] = lastChunkFlag
+	} else {
+		n[len(n)-1] = notLastChunkFlag
+	}
+	return n
+}
+
+// writeFrame writes a frame carrying the last-chunk flag the writer sealed
+// this chunk under, so that the reader never has to guess it from the
+// transport.
+func writeFrame(w io.Writer, frame []byte, last bool) error {
+	var header [1 + 4]byte
+	if last {
+		header[0] = lastChunkFlag
+	} else {
+		header[0] = notLastChunkFlag
+	}
+	binary.BigEndian.PutUint32(header[1:], uint32(len(frame)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+// readFrame reads a frame written by writeFrame, returning the last-chunk
+// flag it was written with.
+func readFrame(r io.Reader) ([]byte, bool, error) {
+	var header [1 + 4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, false, err
+	}
+	last := header[0] == lastChunkFlag
+	frame := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, false, io.ErrUnexpectedEOF
+	}
+	return frame, last, nil
+}
+
+// writer implements io.WriteCloser, buffering plaintext until a full chunk
+// is available to seal.
+type writer struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	buf     []byte
+	counter uint64
+	closed  bool
+}
+
+// NewWriter returns an io.WriteCloser that HIBE-encrypts a symmetric key to
+// id, then seals everything written to it as a sequence of chunkSize chunks
+// under that key, writing the result to w. The final chunk is sealed, with
+// the last-chunk flag set, when Close is called.
+func NewWriter(w io.Writer, params *hibe.Params, id []*big.Int) (io.WriteCloser, error) {
+	seed := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, seed); err != nil {
+		return nil, err
+	}
+	k := hibe.HashToGT(seed)
+
+	kem, err := hibe.Encrypt(rand.Reader, params, id, k)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := deriveKey(k.Marshal())
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return nil, err
+	}
+
+	if err := writeFrame(w, kem.MarshalRaw(), false); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(prefix); err != nil {
+		return nil, err
+	}
+
+	return &writer{w: w, aead: aead, prefix: prefix, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+func (sw *writer) Write(p []byte) (int, error) {
+	if sw.closed {
+		return 0, errors.New("stream: write after close")
+	}
+	written := 0
+	for len(p) > 0 {
+		n := copy(sw.buf[len(sw.buf):cap(sw.buf)], p)
+		sw.buf = sw.buf[:len(sw.buf)+n]
+		p = p[n:]
+		written += n
+
+		if len(sw.buf) == cap(sw.buf) {
+			if err := sw.sealChunk(false); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (sw *writer) sealChunk(last bool) error {
+	n := nonce(sw.prefix, sw.counter, last)
+	sealed := sw.aead.Seal(nil, n, sw.buf, nil)
+	sw.counter++
+	sw.buf = sw.buf[:0]
+	return writeFrame(sw.w, sealed, last)
+}
+
+// Close seals any buffered plaintext as the final chunk, with the
+// last-chunk flag set, and flushes it to the underlying writer.
+func (sw *writer) Close() error {
+	if sw.closed {
+		return nil
+	}
+	sw.closed = true
+	return sw.sealChunk(true)
+}
+
+// reader implements io.Reader, decrypting and buffering one chunk at a time.
+// Each frame carries the last-chunk flag it was sealed under, so the reader
+// never has to guess it from the transport.
+type reader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint64
+	buf     []byte
+	done    bool
+}
+
+// NewReader returns an io.Reader that recovers the plaintext written by a
+// Writer, given the private key for the identity it was encrypted to. It
+// rejects any chunk received after a chunk with the last-chunk flag has
+// already been seen, and returns io.ErrUnexpectedEOF if the underlying
+// reader is exhausted before such a chunk is seen.
+func NewReader(r io.Reader, key *hibe.PrivateKey) (io.Reader, error) {
+	kembytes, _, err := readFrame(r)
+	if err != nil {
+		return nil, err
+	}
+	kem := new(hibe.Ciphertext)
+	if _, ok := kem.UnmarshalRaw(kembytes); !ok {
+		return nil, errors.New("stream: malformed HIBE ciphertext header")
+	}
+	k := hibe.Decrypt(key, kem)
+
+	symkey, err := deriveKey(k.Marshal())
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(symkey)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return &reader{r: r, aead: aead, prefix: prefix}, nil
+}
+
+func (sr *reader) Read(p []byte) (int, error) {
+	for len(sr.buf) == 0 {
+		if sr.done {
+			var extra [1]byte
+			if _, err := io.ReadFull(sr.r, extra[:]); err == nil {
+				return 0, errors.New("stream: data after last chunk")
+			} else if err != io.EOF {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+
+		frame, last, err := readFrame(sr.r)
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		plaintext, err := sr.aead.Open(nil, nonce(sr.prefix, sr.counter, last), frame, nil)
+		if err != nil {
+			return 0, err
+		}
+		sr.counter++
+		sr.buf = plaintext
+		sr.done = last
+	}
+
+	n := copy(p, sr.buf)
+	sr.buf = sr.buf[n:]
+	return n, nil
+}
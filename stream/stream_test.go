@@ -0,0 +1,145 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/samkumar/hibe"
+)
+
+var testID = []*big.Int{big.NewInt(1), big.NewInt(2)}
+
+func TestWriterReaderRoundtrip(t *testing.T) {
+	params, master, err := hibe.Setup(rand.Reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := hibe.KeyGenFromMaster(rand.Reader, params, master, testID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Larger than one chunk so the writer exercises the multi-chunk path.
+	plaintext := make([]byte, 3*chunkSize+17)
+	if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, params, testID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(&buf, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, decrypted) {
+		t.Fatal("Original and decrypted plaintexts differ")
+	}
+}
+
+func TestReaderDetectsTruncation(t *testing.T) {
+	params, master, err := hibe.Setup(rand.Reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := hibe.KeyGenFromMaster(rand.Reader, params, master, testID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := make([]byte, 2*chunkSize+1)
+	if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, params, testID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Cut off the last chunkSize/2 bytes: this drops the final frame and ends
+	// the stream partway through the frame before it, simulating truncation
+	// mid-frame.
+	truncated := buf.Bytes()[:buf.Len()-(chunkSize/2)]
+
+	r, err := NewReader(bytes.NewReader(truncated), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(r); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected io.ErrUnexpectedEOF reading a truncated stream, got %v", err)
+	}
+}
+
+// TestReaderDetectsTruncationAtFrameBoundary drops the final, last-flagged
+// frame of a stream in its entirety, rather than truncating mid-frame. This
+// used to be misdetected: the reader inferred the last-chunk flag by peeking
+// for more transport bytes, so removing the whole last frame made the prior
+// frame look like the last one and fail AEAD authentication under the wrong
+// nonce, instead of surfacing io.ErrUnexpectedEOF.
+func TestReaderDetectsTruncationAtFrameBoundary(t *testing.T) {
+	params, master, err := hibe.Setup(rand.Reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := hibe.KeyGenFromMaster(rand.Reader, params, master, testID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One full chunk plus one byte, so Write seals exactly one non-last
+	// frame and Close seals the remaining byte as the last frame.
+	plaintext := make([]byte, chunkSize+1)
+	if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, params, testID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatal(err)
+	}
+	// The non-last frame has been flushed; everything Close adds from here
+	// is the last frame.
+	boundary := buf.Len()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	truncated := buf.Bytes()[:boundary]
+
+	r, err := NewReader(bytes.NewReader(truncated), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(r); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected io.ErrUnexpectedEOF at an exact frame boundary, got %v", err)
+	}
+}
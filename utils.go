@@ -19,8 +19,10 @@ func geIndex(encoded []byte, index int, len int) []byte {
 	return encoded[index<<geShift : (index+len)<<geShift]
 }
 
-// Marshal encodes the parameters as a byte slice.
-func (params *Params) Marshal() []byte {
+// MarshalRaw encodes the parameters as a byte slice, without the versioned
+// header that Marshal prepends. Kept for callers that already depend on the
+// original headerless wire format.
+func (params *Params) MarshalRaw() []byte {
 	marshalled := make([]byte, (6+len(params.h))<<geShift)
 
 	copy(geIndex(marshalled, 0, 2), params.g.Marshal())
@@ -34,8 +36,9 @@ func (params *Params) Marshal() []byte {
 	return marshalled
 }
 
-// Unmarshal recovers the parameters from an encoded byte slice.
-func (params *Params) Unmarshal(marshalled []byte) (*Params, bool) {
+// UnmarshalRaw recovers the parameters from a byte slice encoded by
+// MarshalRaw, i.e. without a versioned header.
+func (params *Params) UnmarshalRaw(marshalled []byte) (*Params, bool) {
 	if len(marshalled)&((1<<geShift)-1) != 0 {
 		return nil, false
 	}
@@ -76,8 +79,9 @@ func (params *Params) Unmarshal(marshalled []byte) (*Params, bool) {
 	return params, true
 }
 
-// Marshal encodes the private key as a byte slice.
-func (key *PrivateKey) Marshal() []byte {
+// MarshalRaw encodes the private key as a byte slice, without the versioned
+// header that Marshal prepends.
+func (key *PrivateKey) MarshalRaw() []byte {
 	marshalled := make([]byte, (3+len(key.b))<<geShift)
 
 	copy(geIndex(marshalled, 0, 1), key.a0.Marshal())
@@ -89,8 +93,9 @@ func (key *PrivateKey) Marshal() []byte {
 	return marshalled
 }
 
-// Unmarshal recovers the private key from an encoded byte slice.
-func (key *PrivateKey) Unmarshal(marshalled []byte) (*PrivateKey, bool) {
+// UnmarshalRaw recovers the private key from a byte slice encoded by
+// MarshalRaw, i.e. without a versioned header.
+func (key *PrivateKey) UnmarshalRaw(marshalled []byte) (*PrivateKey, bool) {
 	if len(marshalled)&((1<<geShift)-1) != 0 {
 		return nil, false
 	}
@@ -118,8 +123,9 @@ func (key *PrivateKey) Unmarshal(marshalled []byte) (*PrivateKey, bool) {
 	return key, true
 }
 
-// Marshal encodes the ciphertext as a byte slice.
-func (ciphertext *Ciphertext) Marshal() []byte {
+// MarshalRaw encodes the ciphertext as a byte slice, without the versioned
+// header that Marshal prepends.
+func (ciphertext *Ciphertext) MarshalRaw() []byte {
 	marshalled := make([]byte, 9<<geShift)
 
 	copy(geIndex(marshalled, 0, 6), ciphertext.a.Marshal())
@@ -129,22 +135,29 @@ func (ciphertext *Ciphertext) Marshal() []byte {
 	return marshalled
 }
 
-// Unmarshal recovers the ciphertext from an encoded byte slice.
-func (ciphertext *Ciphertext) Unmarshal(marshalled []byte) (*Ciphertext, bool) {
+// UnmarshalRaw recovers the ciphertext from a byte slice encoded by
+// MarshalRaw, i.e. without a versioned header. All three components are
+// unmarshalled unconditionally, regardless of whether an earlier one was
+// malformed, so that whether UnmarshalRaw returns false does not depend on
+// *which* component (if any) is invalid, and in particular does not leak
+// that via an early return. This does not make decoding constant-time
+// overall: bn256's own G1/G2 Unmarshal has data-dependent branches (an
+// infinity check and an IsOnCurve early-return) whose cost still varies with
+// the bytes of a malformed element, and this package does not attempt to
+// mask that.
+func (ciphertext *Ciphertext) UnmarshalRaw(marshalled []byte) (*Ciphertext, bool) {
 	if len(marshalled) != 9<<geShift {
 		return nil, false
 	}
 
 	ciphertext.a = new(bn256.GT)
-	if _, ok := ciphertext.a.Unmarshal(geIndex(marshalled, 0, 6)); !ok {
-		return nil, false
-	}
+	_, aOk := ciphertext.a.Unmarshal(geIndex(marshalled, 0, 6))
 	ciphertext.b = new(bn256.G2)
-	if _, ok := ciphertext.b.Unmarshal(geIndex(marshalled, 6, 2)); !ok {
-		return nil, false
-	}
+	_, bOk := ciphertext.b.Unmarshal(geIndex(marshalled, 6, 2))
 	ciphertext.c = new(bn256.G1)
-	if _, ok := ciphertext.c.Unmarshal(geIndex(marshalled, 8, 1)); !ok {
+	_, cOk := ciphertext.c.Unmarshal(geIndex(marshalled, 8, 1))
+
+	if !aOk || !bOk || !cOk {
 		return nil, false
 	}
 
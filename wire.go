@@ -0,0 +1,229 @@
+package hibe
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// wireMagic identifies a byte slice as HIBE wire format produced by this
+// package.
+const wireMagic = "HIBE"
+
+// wireVersion is the current wire format version. It is bumped whenever the
+// header layout or the meaning of an existing field changes.
+const wireVersion = 1
+
+// wireCurveBN256 identifies golang.org/x/crypto/bn256 as the pairing curve
+// used to encode the group elements that follow the header.
+const wireCurveBN256 = 1
+
+// wireHeaderSize is the size, in bytes, of the header prepended by Marshal:
+// magic (4) + version (1) + type tag (1) + curve identifier (1) + hierarchy
+// depth (2).
+const wireHeaderSize = 4 + 1 + 1 + 1 + 2
+
+// wireType distinguishes which of Params, PrivateKey, Ciphertext, or their
+// BB2 counterparts a header-prefixed byte slice encodes.
+type wireType byte
+
+const (
+	wireTypeParams wireType = iota + 1
+	wireTypePrivateKey
+	wireTypeCiphertext
+	wireTypeParamsBB2
+	wireTypePrivateKeyBB2
+	wireTypeCiphertextBB2
+)
+
+// ErrUnrecognizedWireFormat is returned when a byte slice does not begin
+// with a recognized HIBE header, or when UnmarshalAny encounters a type tag
+// it does not know how to decode or a body it cannot decode as that type.
+var ErrUnrecognizedWireFormat = errors.New("hibe: unrecognized wire format")
+
+// ErrUnsupportedWireVersion is returned when a byte slice's header declares
+// a format version other than the one this package understands.
+var ErrUnsupportedWireVersion = errors.New("hibe: unsupported wire format version")
+
+// ErrUnsupportedCurve is returned when a byte slice's header declares a
+// pairing curve other than the one this package uses.
+var ErrUnsupportedCurve = errors.New("hibe: unsupported curve identifier")
+
+func wireHeader(typ wireType, depth int) []byte {
+	header := make([]byte, wireHeaderSize)
+	copy(header, wireMagic)
+	header[4] = wireVersion
+	header[5] = byte(typ)
+	header[6] = wireCurveBN256
+	binary.BigEndian.PutUint16(header[7:9], uint16(depth))
+	return header
+}
+
+// parseWireHeader validates and strips the header from marshalled, returning
+// the type tag it declares, the hierarchy depth it declares, and the
+// remaining raw-encoded body.
+func parseWireHeader(marshalled []byte) (wireType, int, []byte, error) {
+	if len(marshalled) < wireHeaderSize || string(marshalled[0:4]) != wireMagic {
+		return 0, 0, nil, ErrUnrecognizedWireFormat
+	}
+	if marshalled[4] != wireVersion {
+		return 0, 0, nil, ErrUnsupportedWireVersion
+	}
+	if marshalled[6] != wireCurveBN256 {
+		return 0, 0, nil, ErrUnsupportedCurve
+	}
+	typ := wireType(marshalled[5])
+	depth := int(binary.BigEndian.Uint16(marshalled[7:9]))
+	return typ, depth, marshalled[wireHeaderSize:], nil
+}
+
+// Marshal encodes the parameters as a self-describing byte slice: a
+// versioned header carrying a type tag, curve identifier, and the maximum
+// hierarchy depth, followed by the raw encoding from MarshalRaw.
+func (params *Params) Marshal() []byte {
+	return append(wireHeader(wireTypeParams, params.MaximumDepth()), params.MarshalRaw()...)
+}
+
+// Unmarshal recovers the parameters from a byte slice produced by Marshal.
+func (params *Params) Unmarshal(marshalled []byte) (*Params, bool) {
+	typ, _, body, err := parseWireHeader(marshalled)
+	if err != nil || typ != wireTypeParams {
+		return nil, false
+	}
+	return params.UnmarshalRaw(body)
+}
+
+// Marshal encodes the private key as a self-describing byte slice: a
+// versioned header carrying a type tag, curve identifier, and the key's
+// remaining hierarchy depth, followed by the raw encoding from MarshalRaw.
+func (key *PrivateKey) Marshal() []byte {
+	return append(wireHeader(wireTypePrivateKey, key.DepthLeft()), key.MarshalRaw()...)
+}
+
+// Unmarshal recovers the private key from a byte slice produced by Marshal.
+func (key *PrivateKey) Unmarshal(marshalled []byte) (*PrivateKey, bool) {
+	typ, _, body, err := parseWireHeader(marshalled)
+	if err != nil || typ != wireTypePrivateKey {
+		return nil, false
+	}
+	return key.UnmarshalRaw(body)
+}
+
+// Marshal encodes the ciphertext as a self-describing byte slice: a
+// versioned header carrying a type tag and curve identifier, followed by
+// the raw encoding from MarshalRaw. The hierarchy depth field is always 0,
+// since a ciphertext does not carry one.
+func (ciphertext *Ciphertext) Marshal() []byte {
+	return append(wireHeader(wireTypeCiphertext, 0), ciphertext.MarshalRaw()...)
+}
+
+// Unmarshal recovers the ciphertext from a byte slice produced by Marshal.
+func (ciphertext *Ciphertext) Unmarshal(marshalled []byte) (*Ciphertext, bool) {
+	typ, _, body, err := parseWireHeader(marshalled)
+	if err != nil || typ != wireTypeCiphertext {
+		return nil, false
+	}
+	return ciphertext.UnmarshalRaw(body)
+}
+
+// Marshal encodes the BB2 parameters as a self-describing byte slice, in the
+// same versioned-header style as Params.Marshal.
+func (params *ParamsBB2) Marshal() []byte {
+	return append(wireHeader(wireTypeParamsBB2, params.MaximumDepth()), params.MarshalRaw()...)
+}
+
+// Unmarshal recovers the BB2 parameters from a byte slice produced by
+// Marshal.
+func (params *ParamsBB2) Unmarshal(marshalled []byte) (*ParamsBB2, bool) {
+	typ, _, body, err := parseWireHeader(marshalled)
+	if err != nil || typ != wireTypeParamsBB2 {
+		return nil, false
+	}
+	return params.UnmarshalRaw(body)
+}
+
+// Marshal encodes the BB2 private key as a self-describing byte slice, in
+// the same versioned-header style as PrivateKey.Marshal. The hierarchy
+// depth field is always 0: unlike PrivateKey, a PrivateKeyBB2 cannot
+// delegate, so it has no remaining depth to carry.
+func (key *PrivateKeyBB2) Marshal() []byte {
+	return append(wireHeader(wireTypePrivateKeyBB2, 0), key.MarshalRaw()...)
+}
+
+// Unmarshal recovers the BB2 private key from a byte slice produced by
+// Marshal.
+func (key *PrivateKeyBB2) Unmarshal(marshalled []byte) (*PrivateKeyBB2, bool) {
+	typ, _, body, err := parseWireHeader(marshalled)
+	if err != nil || typ != wireTypePrivateKeyBB2 {
+		return nil, false
+	}
+	return key.UnmarshalRaw(body)
+}
+
+// Marshal encodes the BB2 ciphertext as a self-describing byte slice, in the
+// same versioned-header style as Ciphertext.Marshal. The hierarchy depth
+// field is always 0, since a ciphertext does not carry one.
+func (ciphertext *CiphertextBB2) Marshal() []byte {
+	return append(wireHeader(wireTypeCiphertextBB2, 0), ciphertext.MarshalRaw()...)
+}
+
+// Unmarshal recovers the BB2 ciphertext from a byte slice produced by
+// Marshal.
+func (ciphertext *CiphertextBB2) Unmarshal(marshalled []byte) (*CiphertextBB2, bool) {
+	typ, _, body, err := parseWireHeader(marshalled)
+	if err != nil || typ != wireTypeCiphertextBB2 {
+		return nil, false
+	}
+	return ciphertext.UnmarshalRaw(body)
+}
+
+// UnmarshalAny recovers a Params, PrivateKey, Ciphertext, or their BB2
+// counterparts from a byte slice produced by its Marshal method, dispatching
+// on the type tag in the header. The returned value is a *Params,
+// *PrivateKey, *Ciphertext, *ParamsBB2, *PrivateKeyBB2, or *CiphertextBB2.
+func UnmarshalAny(marshalled []byte) (interface{}, error) {
+	typ, _, body, err := parseWireHeader(marshalled)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typ {
+	case wireTypeParams:
+		params := new(Params)
+		if _, ok := params.UnmarshalRaw(body); !ok {
+			return nil, ErrUnrecognizedWireFormat
+		}
+		return params, nil
+	case wireTypePrivateKey:
+		key := new(PrivateKey)
+		if _, ok := key.UnmarshalRaw(body); !ok {
+			return nil, ErrUnrecognizedWireFormat
+		}
+		return key, nil
+	case wireTypeCiphertext:
+		ciphertext := new(Ciphertext)
+		if _, ok := ciphertext.UnmarshalRaw(body); !ok {
+			return nil, ErrUnrecognizedWireFormat
+		}
+		return ciphertext, nil
+	case wireTypeParamsBB2:
+		params := new(ParamsBB2)
+		if _, ok := params.UnmarshalRaw(body); !ok {
+			return nil, ErrUnrecognizedWireFormat
+		}
+		return params, nil
+	case wireTypePrivateKeyBB2:
+		key := new(PrivateKeyBB2)
+		if _, ok := key.UnmarshalRaw(body); !ok {
+			return nil, ErrUnrecognizedWireFormat
+		}
+		return key, nil
+	case wireTypeCiphertextBB2:
+		ciphertext := new(CiphertextBB2)
+		if _, ok := ciphertext.UnmarshalRaw(body); !ok {
+			return nil, ErrUnrecognizedWireFormat
+		}
+		return ciphertext, nil
+	default:
+		return nil, ErrUnrecognizedWireFormat
+	}
+}
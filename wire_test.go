@@ -0,0 +1,109 @@
+package hibe
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestUnmarshalAny(t *testing.T) {
+	params, master, err := Setup(rand.Reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := KeyGenFromMaster(rand.Reader, params, master, LINEAR_HIERARCHY[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := NewMessage()
+	ciphertext, err := Encrypt(rand.Reader, params, LINEAR_HIERARCHY[:1], message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decodedParams, err := UnmarshalAny(params.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decodedParams.(*Params); !ok {
+		t.Fatal("UnmarshalAny did not return a *Params")
+	}
+
+	decodedKey, err := UnmarshalAny(key.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decodedKey.(*PrivateKey); !ok {
+		t.Fatal("UnmarshalAny did not return a *PrivateKey")
+	}
+
+	decodedCiphertext, err := UnmarshalAny(ciphertext.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decodedCiphertext.(*Ciphertext); !ok {
+		t.Fatal("UnmarshalAny did not return a *Ciphertext")
+	}
+
+	if _, err := UnmarshalAny([]byte("not a HIBE wire format")); err == nil {
+		t.Fatal("expected an error for unrecognized input")
+	}
+}
+
+func TestUnmarshalAnyBB2(t *testing.T) {
+	paramsBB2, masterBB2, err := SetupBB2(rand.Reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyBB2, err := KeyGenBB2(paramsBB2, masterBB2, LINEAR_HIERARCHY[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messageBB2 := NewMessage()
+	ciphertextBB2, err := EncryptBB2(rand.Reader, paramsBB2, LINEAR_HIERARCHY[:1], messageBB2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decodedParams, err := UnmarshalAny(paramsBB2.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decodedParams.(*ParamsBB2); !ok {
+		t.Fatal("UnmarshalAny did not return a *ParamsBB2")
+	}
+
+	decodedKey, err := UnmarshalAny(keyBB2.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decodedKey.(*PrivateKeyBB2); !ok {
+		t.Fatal("UnmarshalAny did not return a *PrivateKeyBB2")
+	}
+
+	decodedCiphertext, err := UnmarshalAny(ciphertextBB2.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decodedCiphertext.(*CiphertextBB2); !ok {
+		t.Fatal("UnmarshalAny did not return a *CiphertextBB2")
+	}
+}
+
+func TestMarshalRawOmitsHeader(t *testing.T) {
+	params, _, err := Setup(rand.Reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.HasPrefix(params.MarshalRaw(), []byte(wireMagic)) {
+		t.Fatal("MarshalRaw should not include the versioned header")
+	}
+	if !bytes.HasPrefix(params.Marshal(), []byte(wireMagic)) {
+		t.Fatal("Marshal should include the versioned header")
+	}
+}